@@ -0,0 +1,201 @@
+package httpcg
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// windowBuckets is the number of slices the rolling window is split into.
+// Each bucket covers Window/windowBuckets and is cleared as it ages out,
+// giving the failure ratio a resolution of one bucket.
+const windowBuckets = 10
+
+// CircuitBreakerConfig configures NewCircuitBreaker.
+type CircuitBreakerConfig struct {
+	// FailureRatio is the fraction of failures, out of MinRequests or
+	// more, that trips the breaker open.
+	FailureRatio float64
+	// MinRequests is the minimum number of requests observed in the
+	// current window before the failure ratio is evaluated.
+	MinRequests int
+	// Window is the length of the rolling window the failure ratio is
+	// computed over. Requests older than Window no longer count towards
+	// it.
+	Window time.Duration
+	// Cooldown is how long the breaker stays open before allowing a
+	// single half-open probe request.
+	Cooldown time.Duration
+}
+
+type bucket struct {
+	failures  int
+	successes int
+}
+
+type hostCounters struct {
+	mu       sync.Mutex
+	state    breakerState
+	openedAt time.Time
+
+	bucketDuration time.Duration
+	buckets        [windowBuckets]bucket
+	current        int
+	bucketStart    time.Time
+}
+
+// advance rotates the ring of buckets so that the current bucket always
+// covers [now-bucketDuration, now], clearing any buckets it skips over.
+// This is what makes the failure ratio a rolling, rather than cumulative,
+// measure.
+func (hc *hostCounters) advance(now time.Time) {
+	if hc.bucketStart.IsZero() {
+		hc.bucketStart = now
+		return
+	}
+
+	elapsed := now.Sub(hc.bucketStart)
+	n := int(elapsed / hc.bucketDuration)
+	if n <= 0 {
+		return
+	}
+	if n >= windowBuckets {
+		hc.buckets = [windowBuckets]bucket{}
+		hc.current = 0
+		hc.bucketStart = now
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		hc.current = (hc.current + 1) % windowBuckets
+		hc.buckets[hc.current] = bucket{}
+	}
+	hc.bucketStart = hc.bucketStart.Add(time.Duration(n) * hc.bucketDuration)
+}
+
+func (hc *hostCounters) totals() (failures, successes int) {
+	for _, b := range hc.buckets {
+		failures += b.failures
+		successes += b.successes
+	}
+	return failures, successes
+}
+
+func (hc *hostCounters) reset(now time.Time) {
+	hc.buckets = [windowBuckets]bucket{}
+	hc.current = 0
+	hc.bucketStart = now
+}
+
+// circuitBreaker is a Breaker tracking a rolling failure ratio per host.
+type circuitBreaker struct {
+	cfg   CircuitBreakerConfig
+	hosts sync.Map // host -> *hostCounters
+}
+
+// NewCircuitBreaker returns a Breaker that trips per host once at least
+// cfg.MinRequests have been observed within cfg.Window and cfg.FailureRatio
+// of them failed, half-opening again after cfg.Cooldown.
+func NewCircuitBreaker(cfg CircuitBreakerConfig) Breaker {
+	if cfg.FailureRatio <= 0 {
+		cfg.FailureRatio = 0.5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	return &circuitBreaker{cfg: cfg}
+}
+
+func (c *circuitBreaker) counters(host string) *hostCounters {
+	v, loaded := c.hosts.Load(host)
+	if loaded {
+		return v.(*hostCounters)
+	}
+	bucketDuration := c.cfg.Window / windowBuckets
+	if bucketDuration <= 0 {
+		bucketDuration = 1
+	}
+	hc := &hostCounters{bucketDuration: bucketDuration}
+	v, _ = c.hosts.LoadOrStore(host, hc)
+	return v.(*hostCounters)
+}
+
+// Allow lets a closed breaker's requests through immediately, rejects
+// everything while open, and, once the cooldown has elapsed, admits
+// exactly one half-open probe at a time — every other request is
+// rejected until that probe's outcome closes or reopens the breaker.
+func (c *circuitBreaker) Allow(host string) error {
+	hc := c.counters(host)
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	hc.advance(time.Now())
+
+	switch hc.state {
+	case breakerOpen:
+		if time.Since(hc.openedAt) < c.cfg.Cooldown {
+			return ErrCircuitOpen
+		}
+		hc.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+func (c *circuitBreaker) RecordSuccess(host string) {
+	hc := c.counters(host)
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	now := time.Now()
+	hc.advance(now)
+
+	if hc.state == breakerHalfOpen {
+		hc.state = breakerClosed
+		hc.reset(now)
+		return
+	}
+
+	hc.buckets[hc.current].successes++
+}
+
+func (c *circuitBreaker) RecordFailure(host string) {
+	hc := c.counters(host)
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	now := time.Now()
+	hc.advance(now)
+
+	if hc.state == breakerHalfOpen {
+		hc.state = breakerOpen
+		hc.openedAt = now
+		hc.reset(now)
+		return
+	}
+
+	hc.buckets[hc.current].failures++
+
+	failures, successes := hc.totals()
+	total := failures + successes
+	if total >= c.cfg.MinRequests && float64(failures)/float64(total) >= c.cfg.FailureRatio {
+		hc.state = breakerOpen
+		hc.openedAt = now
+		hc.reset(now)
+	}
+}
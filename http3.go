@@ -0,0 +1,89 @@
+package httpcg
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// buildHTTP3Transport builds a *http3.RoundTripper from the builder's TLS
+// and timeout settings. idleConnTimeout maps to the QUIC connection's
+// MaxIdleTimeout.
+func (b HTTPClientBuilder) buildHTTP3Transport() *http3.RoundTripper {
+	tlsConfig := b.buildTLSConfig()
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+
+	return &http3.RoundTripper{
+		TLSClientConfig: tlsConfig,
+		QUICConfig: &quic.Config{
+			MaxIdleTimeout:       b.idleConnTimeout,
+			HandshakeIdleTimeout: b.tlsHandshake,
+		},
+	}
+}
+
+// h3Chooser picks between an HTTP/3 transport and a fallback (HTTP/2 or
+// HTTP/1.1) transport per host, based on which hosts have advertised H3
+// support via the Alt-Svc response header.
+type h3Chooser struct {
+	fallback http.RoundTripper
+	h3       http.RoundTripper
+
+	mu        sync.RWMutex
+	advertise map[string]bool
+}
+
+func newH3Chooser(fallback, h3 http.RoundTripper) *h3Chooser {
+	return &h3Chooser{
+		fallback:  fallback,
+		h3:        h3,
+		advertise: make(map[string]bool),
+	}
+}
+
+func (c *h3Chooser) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+
+	c.mu.RLock()
+	useH3 := c.advertise[host]
+	c.mu.RUnlock()
+
+	if useH3 {
+		resp, err := c.h3.RoundTrip(req)
+		if err == nil {
+			return resp, nil
+		}
+		// Fall through to the H2/H1.1 path if the H3 connection failed.
+	}
+
+	resp, err := c.fallback.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if advertisesH3(resp.Header.Get("Alt-Svc")) {
+		c.mu.Lock()
+		c.advertise[host] = true
+		c.mu.Unlock()
+	}
+
+	return resp, nil
+}
+
+// advertisesH3 reports whether an Alt-Svc header value lists an "h3"
+// protocol entry, e.g. `h3=":443"; ma=3600, h2=":443"; ma=3600`.
+func advertisesH3(altSvc string) bool {
+	for _, entry := range strings.Split(altSvc, ",") {
+		entry = strings.TrimSpace(entry)
+		if strings.HasPrefix(entry, "h3=") || strings.HasPrefix(entry, "h3-") {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,10 @@
 package httpcg
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
@@ -22,6 +26,18 @@ type HTTPClientBuilder struct {
 	proxy                  func(*http.Request) (*url.URL, error)
 	http2                  bool
 	storeCookies           bool
+	tlsConfig              *tls.Config
+	rootCAs                *x509.CertPool
+	clientCertificates     []tls.Certificate
+	serverName             string
+	insecureSkipVerify     bool
+	traceHooks             *TraceHooks
+	retryPolicy            *RetryPolicy
+	breaker                Breaker
+	hostPoolOverrides      map[string]HostPool
+	dnsCacheTTL            time.Duration
+	middleware             []func(http.RoundTripper) http.RoundTripper
+	http3                  bool
 }
 
 // NewBuilder will return a new builder for the http.Client.
@@ -71,7 +87,7 @@ func (b HTTPClientBuilder) ExpectContinueTimeout(t time.Duration) HTTPClientBuil
 }
 
 func (b HTTPClientBuilder) WithKeepAlive(t time.Duration) HTTPClientBuilder {
-	b.expectContinueTimeout = t
+	b.keepAlive = t
 	return b
 }
 
@@ -81,7 +97,7 @@ func (b HTTPClientBuilder) IdleConnTimeout(t time.Duration) HTTPClientBuilder {
 }
 
 func (b HTTPClientBuilder) ResponseHeaderTimeout(t time.Duration) HTTPClientBuilder {
-	b.idleConnTimeout = t
+	b.responseHeaderTimeout = t
 	return b
 }
 
@@ -95,21 +111,86 @@ func (b HTTPClientBuilder) WithCookies() HTTPClientBuilder {
 	return b
 }
 
+// WithTLSConfig sets a full *tls.Config to use as the base of the
+// transport's TLS settings. The individual TLS options below are applied on
+// top of a copy of it and override the fields they set.
+func (b HTTPClientBuilder) WithTLSConfig(cfg *tls.Config) HTTPClientBuilder {
+	b.tlsConfig = cfg
+	return b
+}
+
+// WithRootCAs sets the pool of CA certificates used to verify the server
+// certificate, instead of the host's default root set.
+func (b HTTPClientBuilder) WithRootCAs(pool *x509.CertPool) HTTPClientBuilder {
+	b.rootCAs = pool
+	return b
+}
+
+// WithClientCertificate adds a certificate to present to the server for mTLS.
+func (b HTTPClientBuilder) WithClientCertificate(cert tls.Certificate) HTTPClientBuilder {
+	b.clientCertificates = append(b.clientCertificates, cert)
+	return b
+}
+
+// WithServerName overrides the ServerName used for SNI and certificate
+// verification.
+func (b HTTPClientBuilder) WithServerName(name string) HTTPClientBuilder {
+	b.serverName = name
+	return b
+}
+
+// WithInsecureSkipVerify disables server certificate verification. It is
+// meant for internal testing only and must never be used in production.
+func (b HTTPClientBuilder) WithInsecureSkipVerify() HTTPClientBuilder {
+	b.insecureSkipVerify = true
+	return b
+}
+
+// WithMiddleware installs a chain of RoundTripper wrappers around the
+// base transport. The first middleware given is the outermost layer, so
+// it sees each request before any of the others. See the httpcg/middleware
+// subpackage for ready-made middleware.
+func (b HTTPClientBuilder) WithMiddleware(mw ...func(http.RoundTripper) http.RoundTripper) HTTPClientBuilder {
+	b.middleware = append(b.middleware, mw...)
+	return b
+}
+
+// WithHTTP3 builds the client on a QUIC (HTTP/3) transport instead of the
+// standard http.Transport. When combined with WithHTTP2, requests are
+// routed through a chooser that prefers H3 once the origin has advertised
+// it via Alt-Svc, falling back to H2/H1.1 until then.
+func (b HTTPClientBuilder) WithHTTP3() HTTPClientBuilder {
+	b.http3 = true
+	return b
+}
+
 // Build will grab all the builder settings and generate an http.client
 func (b HTTPClientBuilder) Build() (*http.Client, error) {
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		KeepAlive: b.keepAlive,
+		Timeout:   b.connectionTimeout,
+	}
+
+	dialContext := dialer.DialContext
+	if b.dnsCacheTTL > 0 {
+		dialContext = newDNSCache(b.dnsCacheTTL).dialContext(dialer)
+	}
+
 	tr := &http.Transport{
 		ResponseHeaderTimeout: b.responseHeaderTimeout,
 		Proxy:                 b.proxy,
-		DialContext: (&net.Dialer{
-			KeepAlive: b.keepAlive,
-			Timeout:   b.connectionTimeout,
-		}).DialContext,
+		DialContext:           dialContext,
 		MaxIdleConns:          b.maxIdleConnections,
 		IdleConnTimeout:       b.idleConnTimeout,
 		TLSHandshakeTimeout:   b.tlsHandshake,
 		MaxIdleConnsPerHost:   b.maxHostIdleConnections,
 		ExpectContinueTimeout: b.expectContinueTimeout,
 		ForceAttemptHTTP2:     b.http2,
+		TLSClientConfig:       b.buildTLSConfig(),
 	}
 
 	if b.http2 {
@@ -119,18 +200,168 @@ func (b HTTPClientBuilder) Build() (*http.Client, error) {
 		}
 	}
 
+	var transport http.RoundTripper = tr
+	if len(b.hostPoolOverrides) > 0 {
+		transport = addHostPoolOverrides(tr, b.hostPoolOverrides)
+	}
+
+	if b.http3 {
+		h3tr := b.buildHTTP3Transport()
+		if b.http2 {
+			transport = newH3Chooser(transport, h3tr)
+		} else {
+			transport = h3tr
+		}
+	}
+
+	var client *http.Client
 	if b.storeCookies {
-		return addCookies(tr)
+		var err error
+		client, err = addCookies(transport)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		client = &http.Client{Transport: transport}
+	}
+
+	for i := len(b.middleware) - 1; i >= 0; i-- {
+		client.Transport = b.middleware[i](client.Transport)
+	}
+
+	if b.retryPolicy != nil || b.breaker != nil {
+		policy := noRetryPolicy()
+		if b.retryPolicy != nil {
+			policy = *b.retryPolicy
+		}
+		client.Transport = addRetry(client.Transport, policy, b.breaker)
 	}
 
-	return &http.Client{Transport: tr}, nil
+	if b.traceHooks != nil {
+		client.Transport = addTrace(client.Transport, *b.traceHooks)
+	}
+
+	return client, nil
+}
+
+// MustBuild is like Build but panics if the builder settings are invalid
+// or the client could not be constructed.
+func (b HTTPClientBuilder) MustBuild() *http.Client {
+	client, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// validate reports every invalid combination of settings found in b,
+// wrapped into a single error, or nil if b is valid.
+func (b HTTPClientBuilder) validate() error {
+	var errs []error
+
+	durations := map[string]time.Duration{
+		"ConnectionTimeout":     b.connectionTimeout,
+		"KeepAlive":             b.keepAlive,
+		"ExpectContinueTimeout": b.expectContinueTimeout,
+		"IdleConnTimeout":       b.idleConnTimeout,
+		"ResponseHeaderTimeout": b.responseHeaderTimeout,
+		"TLSHandshakeTimeout":   b.tlsHandshake,
+		"DNSCache TTL":          b.dnsCacheTTL,
+	}
+	for name, d := range durations {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %s", name, d))
+		}
+	}
+
+	if b.maxIdleConnections < 0 {
+		errs = append(errs, fmt.Errorf("MaxIdleConn: all must not be negative, got %d", b.maxIdleConnections))
+	}
+	if b.maxHostIdleConnections < 0 {
+		errs = append(errs, fmt.Errorf("MaxIdleConn: host must not be negative, got %d", b.maxHostIdleConnections))
+	}
+	if b.maxIdleConnections > 0 && b.maxHostIdleConnections > b.maxIdleConnections {
+		errs = append(errs, fmt.Errorf("MaxIdleConn: host (%d) must not be bigger than all (%d)",
+			b.maxHostIdleConnections, b.maxIdleConnections))
+	}
+
+	for host, pool := range b.hostPoolOverrides {
+		if pool.MaxIdleConnsPerHost < 0 {
+			errs = append(errs, fmt.Errorf("WithHostPoolOverrides: %s: MaxIdleConnsPerHost must not be negative, got %d", host, pool.MaxIdleConnsPerHost))
+		}
+		if pool.MaxConnsPerHost < 0 {
+			errs = append(errs, fmt.Errorf("WithHostPoolOverrides: %s: MaxConnsPerHost must not be negative, got %d", host, pool.MaxConnsPerHost))
+		}
+	}
+
+	if b.retryPolicy != nil {
+		if b.retryPolicy.MaxAttempts < 1 {
+			errs = append(errs, fmt.Errorf("WithRetry: MaxAttempts must be at least 1, got %d", b.retryPolicy.MaxAttempts))
+		}
+		if b.retryPolicy.BaseDelay > b.retryPolicy.MaxDelay {
+			errs = append(errs, fmt.Errorf("WithRetry: BaseDelay (%s) must not be bigger than MaxDelay (%s)",
+				b.retryPolicy.BaseDelay, b.retryPolicy.MaxDelay))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// buildTLSConfig assembles the *tls.Config from the individual TLS options,
+// returning nil when none were set so the transport falls back to its
+// defaults.
+func (b HTTPClientBuilder) buildTLSConfig() *tls.Config {
+	if b.tlsConfig == nil && b.rootCAs == nil && len(b.clientCertificates) == 0 &&
+		b.serverName == "" && !b.insecureSkipVerify {
+		return nil
+	}
+
+	cfg := b.tlsConfig
+	if cfg == nil {
+		cfg = &tls.Config{}
+	} else {
+		cfg = cfg.Clone()
+	}
+
+	if b.rootCAs != nil {
+		cfg.RootCAs = b.rootCAs
+	}
+	if len(b.clientCertificates) > 0 {
+		cfg.Certificates = append(cfg.Certificates, b.clientCertificates...)
+	}
+	if b.serverName != "" {
+		cfg.ServerName = b.serverName
+	}
+	if b.insecureSkipVerify {
+		cfg.InsecureSkipVerify = true
+	}
+
+	return cfg
 }
 
 func addHTTP2(tr *http.Transport) error {
+	// NextProtos must advertise "h2" before ConfigureTransport runs, or
+	// ALPN negotiation over a custom TLSClientConfig will silently stay on
+	// HTTP/1.1.
+	if tr.TLSClientConfig == nil {
+		tr.TLSClientConfig = &tls.Config{}
+	}
+	if !containsString(tr.TLSClientConfig.NextProtos, "h2") {
+		tr.TLSClientConfig.NextProtos = append(tr.TLSClientConfig.NextProtos, "h2")
+	}
 	return http2.ConfigureTransport(tr)
 }
 
-func addCookies(tr *http.Transport) (*http.Client, error) {
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func addCookies(tr http.RoundTripper) (*http.Client, error) {
 	jar, err := cookiejar.New(&cookiejar.Options{})
 	if err != nil {
 		return nil, err
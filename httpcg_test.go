@@ -0,0 +1,275 @@
+package httpcg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBuilderMethods(t *testing.T) {
+	tests := []struct {
+		name  string
+		apply func(HTTPClientBuilder) HTTPClientBuilder
+		check func(t *testing.T, b HTTPClientBuilder)
+	}{
+		{
+			name:  "MaxIdleConn",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.MaxIdleConn(50, 5) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.maxIdleConnections != 50 || b.maxHostIdleConnections != 5 {
+					t.Fatalf("got all=%d host=%d, want all=50 host=5", b.maxIdleConnections, b.maxHostIdleConnections)
+				}
+			},
+		},
+		{
+			name:  "ConnectionTimeout",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.ConnectionTimeout(2 * time.Second) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.connectionTimeout != 2*time.Second {
+					t.Fatalf("got %s, want 2s", b.connectionTimeout)
+				}
+			},
+		},
+		{
+			name:  "TLSHandshakeTimeout",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.TLSHandshakeTimeout(2 * time.Second) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.tlsHandshake != 2*time.Second {
+					t.Fatalf("got %s, want 2s", b.tlsHandshake)
+				}
+			},
+		},
+		{
+			name:  "ExpectContinueTimeout",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.ExpectContinueTimeout(2 * time.Second) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.expectContinueTimeout != 2*time.Second {
+					t.Fatalf("got %s, want 2s", b.expectContinueTimeout)
+				}
+			},
+		},
+		{
+			name:  "WithKeepAlive",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.WithKeepAlive(2 * time.Second) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.keepAlive != 2*time.Second {
+					t.Fatalf("WithKeepAlive wrote to the wrong field, got keepAlive=%s", b.keepAlive)
+				}
+			},
+		},
+		{
+			name:  "IdleConnTimeout",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.IdleConnTimeout(2 * time.Second) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.idleConnTimeout != 2*time.Second {
+					t.Fatalf("got %s, want 2s", b.idleConnTimeout)
+				}
+			},
+		},
+		{
+			name:  "ResponseHeaderTimeout",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.ResponseHeaderTimeout(2 * time.Second) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.responseHeaderTimeout != 2*time.Second {
+					t.Fatalf("ResponseHeaderTimeout wrote to the wrong field, got responseHeaderTimeout=%s", b.responseHeaderTimeout)
+				}
+			},
+		},
+		{
+			name:  "WithHTTP2",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.WithHTTP2() },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if !b.http2 {
+					t.Fatal("http2 flag not set")
+				}
+			},
+		},
+		{
+			name:  "WithCookies",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.WithCookies() },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if !b.storeCookies {
+					t.Fatal("storeCookies flag not set")
+				}
+			},
+		},
+		{
+			name: "WithTLSConfig",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder {
+				return b.WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS13})
+			},
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.tlsConfig == nil || b.tlsConfig.MinVersion != tls.VersionTLS13 {
+					t.Fatal("tlsConfig not set")
+				}
+			},
+		},
+		{
+			name:  "WithRootCAs",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.WithRootCAs(x509.NewCertPool()) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.rootCAs == nil {
+					t.Fatal("rootCAs not set")
+				}
+			},
+		},
+		{
+			name:  "WithClientCertificate",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.WithClientCertificate(tls.Certificate{}) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if len(b.clientCertificates) != 1 {
+					t.Fatalf("got %d client certificates, want 1", len(b.clientCertificates))
+				}
+			},
+		},
+		{
+			name:  "WithServerName",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.WithServerName("example.com") },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.serverName != "example.com" {
+					t.Fatalf("got %q, want example.com", b.serverName)
+				}
+			},
+		},
+		{
+			name:  "WithInsecureSkipVerify",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.WithInsecureSkipVerify() },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if !b.insecureSkipVerify {
+					t.Fatal("insecureSkipVerify flag not set")
+				}
+			},
+		},
+		{
+			name:  "WithTrace",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.WithTrace(TraceHooks{}) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.traceHooks == nil {
+					t.Fatal("traceHooks not set")
+				}
+			},
+		},
+		{
+			name:  "WithRetry",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.WithRetry(RetryPolicy{MaxAttempts: 5}) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.retryPolicy == nil || b.retryPolicy.MaxAttempts != 5 {
+					t.Fatal("retryPolicy not set")
+				}
+			},
+		},
+		{
+			name: "WithCircuitBreaker",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder {
+				return b.WithCircuitBreaker(NewCircuitBreaker(CircuitBreakerConfig{}))
+			},
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.breaker == nil {
+					t.Fatal("breaker not set")
+				}
+			},
+		},
+		{
+			name: "WithHostPoolOverrides",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder {
+				return b.WithHostPoolOverrides(map[string]HostPool{"api.example.com": {MaxConnsPerHost: 5}})
+			},
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.hostPoolOverrides["api.example.com"].MaxConnsPerHost != 5 {
+					t.Fatal("hostPoolOverrides not set")
+				}
+			},
+		},
+		{
+			name:  "WithDNSCache",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.WithDNSCache(time.Minute) },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if b.dnsCacheTTL != time.Minute {
+					t.Fatalf("got %s, want 1m", b.dnsCacheTTL)
+				}
+			},
+		},
+		{
+			name: "WithMiddleware",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder {
+				return b.WithMiddleware(func(rt http.RoundTripper) http.RoundTripper { return rt })
+			},
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if len(b.middleware) != 1 {
+					t.Fatalf("got %d middleware, want 1", len(b.middleware))
+				}
+			},
+		},
+		{
+			name:  "WithHTTP3",
+			apply: func(b HTTPClientBuilder) HTTPClientBuilder { return b.WithHTTP3() },
+			check: func(t *testing.T, b HTTPClientBuilder) {
+				if !b.http3 {
+					t.Fatal("http3 flag not set")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.apply(NewBuilder())
+			tt.check(t, b)
+		})
+	}
+}
+
+func TestBuildValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder HTTPClientBuilder
+		wantErr bool
+	}{
+		{
+			name:    "defaults are valid",
+			builder: NewBuilder(),
+			wantErr: false,
+		},
+		{
+			name:    "negative connection timeout",
+			builder: NewBuilder().ConnectionTimeout(-1),
+			wantErr: true,
+		},
+		{
+			name:    "host idle connections bigger than all",
+			builder: NewBuilder().MaxIdleConn(5, 10),
+			wantErr: true,
+		},
+		{
+			name:    "retry with zero max attempts",
+			builder: NewBuilder().WithRetry(RetryPolicy{MaxAttempts: 0, BaseDelay: time.Second, MaxDelay: time.Second}),
+			wantErr: false, // WithRetry normalizes MaxAttempts<=0 to the default
+		},
+		{
+			name:    "negative host pool override",
+			builder: NewBuilder().WithHostPoolOverrides(map[string]HostPool{"x": {MaxConnsPerHost: -1}}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMustBuildPanicsOnInvalidSettings(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected MustBuild to panic on invalid settings")
+		}
+	}()
+
+	NewBuilder().ConnectionTimeout(-1).MustBuild()
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import "net/http"
+
+// TokenSource returns the current bearer token, letting callers refresh
+// or rotate it between calls.
+type TokenSource func() (string, error)
+
+// BearerAuth sets the Authorization header to "Bearer <token>" on every
+// request, fetching the token from source each time.
+func BearerAuth(source TokenSource) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &bearerAuthRoundTripper{next: next, source: source}
+	}
+}
+
+type bearerAuthRoundTripper struct {
+	next   http.RoundTripper
+	source TokenSource
+}
+
+func (b *bearerAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := b.source()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return b.next.RoundTrip(req)
+}
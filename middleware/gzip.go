@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+)
+
+// GZipDecompress requests gzip encoding explicitly and decompresses the
+// response itself, disabling Go's built-in transparent gzip handling (Go
+// skips that automatic decompression whenever Accept-Encoding is already
+// set), the way MinIO's client does. RoundTrippers wrapped inside this one
+// see the raw compressed body; the response it returns carries the
+// decompressed body, with Content-Length removed and ContentLength set to
+// -1. It does not record the compressed byte count itself.
+func GZipDecompress() func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &gzipRoundTripper{next: next}
+	}
+}
+
+type gzipRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (g *gzipRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := g.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp, nil
+	}
+
+	gr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.Body = &gzipReadCloser{gr: gr, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+
+	return resp, nil
+}
+
+type gzipReadCloser struct {
+	gr  *gzip.Reader
+	raw io.ReadCloser
+}
+
+func (g *gzipReadCloser) Read(p []byte) (int, error) {
+	return g.gr.Read(p)
+}
+
+func (g *gzipReadCloser) Close() error {
+	if err := g.gr.Close(); err != nil {
+		g.raw.Close()
+		return err
+	}
+	return g.raw.Close()
+}
@@ -0,0 +1,37 @@
+// Package middleware provides a small set of RoundTripper wrappers meant
+// to be installed via HTTPClientBuilder.WithMiddleware.
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Logger is satisfied by *log.Logger, letting callers pass it directly.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Logging logs the method, URL, status code and duration of every
+// request.
+func Logging(logger Logger) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &loggingRoundTripper{next: next, logger: logger}
+	}
+}
+
+type loggingRoundTripper struct {
+	next   http.RoundTripper
+	logger Logger
+}
+
+func (l *loggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := l.next.RoundTrip(req)
+	if err != nil {
+		l.logger.Printf("%s %s failed after %s: %v", req.Method, req.URL, time.Since(start), err)
+		return resp, err
+	}
+	l.logger.Printf("%s %s %d in %s", req.Method, req.URL, resp.StatusCode, time.Since(start))
+	return resp, err
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestID sets an X-Request-Id header with a random hex identifier on
+// every request that doesn't already carry one.
+func RequestID() func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &requestIDRoundTripper{next: next}
+	}
+}
+
+type requestIDRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (r *requestIDRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("X-Request-Id") != "" {
+		return r.next.RoundTrip(req)
+	}
+
+	id, err := newRequestID()
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Request-Id", id)
+	return r.next.RoundTrip(req)
+}
+
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
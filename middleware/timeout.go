@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ContextTimeout applies d as the request's context deadline whenever the
+// incoming context doesn't already carry one.
+func ContextTimeout(d time.Duration) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &contextTimeoutRoundTripper{next: next, d: d}
+	}
+}
+
+type contextTimeoutRoundTripper struct {
+	next http.RoundTripper
+	d    time.Duration
+}
+
+func (c *contextTimeoutRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := req.Context().Deadline(); ok {
+		return c.next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), c.d)
+
+	resp, err := c.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return resp, err
+	}
+
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import "net/http"
+
+// UserAgent overrides the User-Agent header on every request.
+func UserAgent(ua string) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &userAgentRoundTripper{next: next, ua: ua}
+	}
+}
+
+type userAgentRoundTripper struct {
+	next http.RoundTripper
+	ua   string
+}
+
+func (u *userAgentRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", u.ua)
+	return u.next.RoundTrip(req)
+}
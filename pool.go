@@ -0,0 +1,148 @@
+package httpcg
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HostPool overrides the connection pool limits for a single host.
+type HostPool struct {
+	// MaxIdleConnsPerHost overrides Transport.MaxIdleConnsPerHost for this host.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost overrides Transport.MaxConnsPerHost for this host.
+	MaxConnsPerHost int
+}
+
+// WithHostPoolOverrides sets per-host connection pool limits, keyed by
+// host (as found in a request URL's Host field, e.g. "api.example.com").
+// Hosts not present in overrides keep the builder's global MaxIdleConn
+// settings.
+func (b HTTPClientBuilder) WithHostPoolOverrides(overrides map[string]HostPool) HTTPClientBuilder {
+	b.hostPoolOverrides = overrides
+	return b
+}
+
+// WithDNSCache installs a DialContext that resolves hosts through a
+// cached net.Resolver, keeping results for ttl, and dials every returned
+// address in turn until one succeeds rather than leaving each connection
+// attempt to the OS resolver.
+func (b HTTPClientBuilder) WithDNSCache(ttl time.Duration) HTTPClientBuilder {
+	b.dnsCacheTTL = ttl
+	return b
+}
+
+// hostPoolRoundTripper dispatches requests to a per-host *http.Transport
+// cloned from base, so that MaxIdleConnsPerHost and MaxConnsPerHost can be
+// tuned per host even though http.Transport only exposes a single global
+// value for each.
+type hostPoolRoundTripper struct {
+	base      *http.Transport
+	overrides map[string]HostPool
+
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}
+
+func addHostPoolOverrides(base *http.Transport, overrides map[string]HostPool) http.RoundTripper {
+	return &hostPoolRoundTripper{
+		base:       base,
+		overrides:  overrides,
+		transports: make(map[string]*http.Transport, len(overrides)),
+	}
+}
+
+func (h *hostPoolRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	pool, ok := h.overrides[req.URL.Host]
+	if !ok {
+		return h.base.RoundTrip(req)
+	}
+
+	h.mu.Lock()
+	tr, ok := h.transports[req.URL.Host]
+	if !ok {
+		tr = h.base.Clone()
+		if pool.MaxIdleConnsPerHost > 0 {
+			tr.MaxIdleConnsPerHost = pool.MaxIdleConnsPerHost
+		}
+		if pool.MaxConnsPerHost > 0 {
+			tr.MaxConnsPerHost = pool.MaxConnsPerHost
+		}
+		h.transports[req.URL.Host] = tr
+	}
+	h.mu.Unlock()
+
+	return tr.RoundTrip(req)
+}
+
+// dnsCache resolves and caches host addresses for a bounded ttl.
+type dnsCache struct {
+	ttl      time.Duration
+	resolver *net.Resolver
+
+	mu      sync.Mutex
+	entries map[string]dnsCacheEntry
+}
+
+type dnsCacheEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		ttl:      ttl,
+		resolver: net.DefaultResolver,
+		entries:  make(map[string]dnsCacheEntry),
+	}
+}
+
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[host]; ok && time.Now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.addrs, nil
+	}
+	c.mu.Unlock()
+
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[host] = dnsCacheEntry{addrs: addrs, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return addrs, nil
+}
+
+// dialContext wraps dialer's DialContext so that, for host:port addresses,
+// it resolves the host through the cache and tries every returned address
+// one after another, in the order the resolver returned them, instead of
+// dialing the address the OS resolver happened to pick.
+func (c *dnsCache) dialContext(dialer *net.Dialer) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		addrs, err := c.lookup(ctx, host)
+		if err != nil || len(addrs) == 0 {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		var lastErr error
+		for _, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
@@ -0,0 +1,278 @@
+package httpcg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a request is rejected because its
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("httpcg: circuit breaker is open")
+
+var defaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+var defaultIdempotentMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPut,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// RetryPolicy configures how the retrying RoundTripper installed by
+// WithRetry behaves.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first one.
+	MaxAttempts int
+	// RetryableStatusCodes lists the response status codes that should be
+	// retried. Defaults to 429, 502, 503 and 504.
+	RetryableStatusCodes []int
+	// RetryableError reports whether an error returned by the underlying
+	// RoundTripper (network errors, timeouts, ...) should be retried.
+	RetryableError func(error) bool
+	// BaseDelay is the delay before the first retry. Each subsequent retry
+	// doubles it, capped at MaxDelay, with up to ±50% jitter applied.
+	BaseDelay time.Duration
+	// MaxDelay caps the computed backoff, before jitter is applied.
+	MaxDelay time.Duration
+	// RetryPost opts in to retrying POST requests, which are not
+	// idempotent by default.
+	RetryPost bool
+}
+
+// DefaultRetryPolicy returns the policy applied when WithRetry is called
+// with a zero-value RetryPolicy.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:          3,
+		RetryableStatusCodes: defaultRetryableStatusCodes,
+		BaseDelay:            100 * time.Millisecond,
+		MaxDelay:             5 * time.Second,
+	}
+}
+
+// noRetryPolicy returns a policy that never retries, used to wrap the
+// transport with a circuit breaker alone when WithRetry wasn't also
+// called.
+func noRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 1,
+		BaseDelay:   DefaultRetryPolicy().BaseDelay,
+		MaxDelay:    DefaultRetryPolicy().MaxDelay,
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy().MaxAttempts
+	}
+	if len(p.RetryableStatusCodes) == 0 {
+		p.RetryableStatusCodes = defaultRetryableStatusCodes
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryPolicy().BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryPolicy().MaxDelay
+	}
+	return p
+}
+
+func (p RetryPolicy) isRetryableStatus(code int) bool {
+	for _, c := range p.RetryableStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) isIdempotent(method string) bool {
+	if p.RetryPost && method == http.MethodPost {
+		return true
+	}
+	for _, m := range defaultIdempotentMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << uint(attempt)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	jitter := time.Duration((rand.Float64()*2 - 1) * float64(d) * 0.5)
+	d += jitter
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// Breaker is a per-host circuit breaker. Allow is consulted before every
+// request; RecordSuccess and RecordFailure report the outcome.
+type Breaker interface {
+	Allow(host string) error
+	RecordSuccess(host string)
+	RecordFailure(host string)
+}
+
+// WithRetry wraps the built client's Transport in a RoundTripper that
+// retries failed requests according to policy.
+func (b HTTPClientBuilder) WithRetry(policy RetryPolicy) HTTPClientBuilder {
+	p := policy.withDefaults()
+	b.retryPolicy = &p
+	return b
+}
+
+// WithCircuitBreaker wraps the built client's Transport so that requests
+// are rejected with ErrCircuitOpen while cb reports its circuit as open.
+func (b HTTPClientBuilder) WithCircuitBreaker(cb Breaker) HTTPClientBuilder {
+	b.breaker = cb
+	return b
+}
+
+type retryRoundTripper struct {
+	next    http.RoundTripper
+	policy  RetryPolicy
+	breaker Breaker
+}
+
+func addRetry(tr http.RoundTripper, policy RetryPolicy, breaker Breaker) http.RoundTripper {
+	return &retryRoundTripper{next: tr, policy: policy, breaker: breaker}
+}
+
+func (r *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := bufferBody(req); err != nil {
+		return nil, err
+	}
+
+	retryable := r.policy.isIdempotent(req.Method)
+	host := req.URL.Host
+
+	if r.breaker != nil {
+		if aerr := r.breaker.Allow(host); aerr != nil {
+			return nil, aerr
+		}
+	}
+
+	var resp *http.Response
+	var err error
+
+attempts:
+	for attempt := 0; attempt < r.policy.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if rerr := rewindBody(req); rerr != nil {
+				err = rerr
+				break
+			}
+		}
+
+		resp, err = r.next.RoundTrip(req)
+
+		if !retryable || attempt == r.policy.MaxAttempts-1 {
+			break
+		}
+
+		if err != nil {
+			if r.policy.RetryableError == nil || !r.policy.RetryableError(err) {
+				break
+			}
+		} else if !r.policy.isRetryableStatus(resp.StatusCode) {
+			break
+		}
+
+		delay := r.policy.backoff(attempt)
+		if resp != nil {
+			if ra, ok := retryAfter(resp); ok {
+				delay = ra
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			resp, err = nil, req.Context().Err()
+			break attempts
+		}
+	}
+
+	// The breaker records a single outcome for the whole logical request,
+	// not per attempt, so a retried request doesn't inflate the rolling
+	// failure ratio by MaxAttempts.
+	if r.breaker != nil {
+		if err != nil || (resp != nil && resp.StatusCode >= 500) {
+			r.breaker.RecordFailure(host)
+		} else {
+			r.breaker.RecordSuccess(host)
+		}
+	}
+
+	return resp, err
+}
+
+// retryAfter parses the Retry-After header in either its seconds or
+// HTTP-date form.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// bufferBody reads req.Body into memory and installs GetBody so the body
+// can be replayed across retries, unless GetBody is already set.
+func bufferBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, err = req.GetBody()
+	return err
+}
+
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
@@ -0,0 +1,136 @@
+package httpcg
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// TraceHooks holds the callbacks invoked for each phase of a request's
+// round trip. Every callback receives the request's host, since a single
+// TraceHooks is shared across every request a client makes. Every field
+// is optional; nil callbacks are simply skipped.
+type TraceHooks struct {
+	DNSStart             func(host string)
+	DNSDone              func(host string, d time.Duration, err error)
+	ConnectStart         func(host, network, addr string)
+	ConnectDone          func(host, network, addr string, d time.Duration, err error)
+	TLSHandshakeStart    func(host string)
+	TLSHandshakeDone     func(host string, d time.Duration, state tls.ConnectionState, err error)
+	GotFirstResponseByte func(host string, d time.Duration)
+	WroteRequest         func(host string, d time.Duration, err error)
+	Total                func(host string, d time.Duration, err error)
+}
+
+// MetricSink receives the latency observed for each phase of a request,
+// keyed by host, so callers can wire up per-host histograms without
+// reimplementing the httptrace plumbing.
+type MetricSink interface {
+	Observe(host, phase string, d time.Duration)
+}
+
+// PrometheusHooks builds a TraceHooks that reports connect, TLS handshake
+// and time-to-first-byte latencies to sink under the phases "connect",
+// "tls_handshake", "ttfb" and "total", keyed by each request's own host so
+// a single client shared across upstreams still gets per-host histograms.
+func PrometheusHooks(sink MetricSink) TraceHooks {
+	return TraceHooks{
+		ConnectDone: func(host, network, addr string, d time.Duration, err error) {
+			if err == nil {
+				sink.Observe(host, "connect", d)
+			}
+		},
+		TLSHandshakeDone: func(host string, d time.Duration, state tls.ConnectionState, err error) {
+			if err == nil {
+				sink.Observe(host, "tls_handshake", d)
+			}
+		},
+		GotFirstResponseByte: func(host string, d time.Duration) {
+			sink.Observe(host, "ttfb", d)
+		},
+		Total: func(host string, d time.Duration, err error) {
+			if err == nil {
+				sink.Observe(host, "total", d)
+			}
+		},
+	}
+}
+
+// WithTrace wraps the built client's Transport in a RoundTripper that
+// injects an httptrace.ClientTrace into each request's context, invoking
+// hooks for every phase of the round trip.
+func (b HTTPClientBuilder) WithTrace(hooks TraceHooks) HTTPClientBuilder {
+	b.traceHooks = &hooks
+	return b
+}
+
+type traceRoundTripper struct {
+	next  http.RoundTripper
+	hooks TraceHooks
+}
+
+func (t *traceRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	var dnsStart, connectStart, tlsStart time.Time
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			dnsStart = time.Now()
+			if t.hooks.DNSStart != nil {
+				t.hooks.DNSStart(req.Host)
+			}
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			if t.hooks.DNSDone != nil {
+				t.hooks.DNSDone(req.Host, time.Since(dnsStart), info.Err)
+			}
+		},
+		ConnectStart: func(network, addr string) {
+			connectStart = time.Now()
+			if t.hooks.ConnectStart != nil {
+				t.hooks.ConnectStart(req.Host, network, addr)
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if t.hooks.ConnectDone != nil {
+				t.hooks.ConnectDone(req.Host, network, addr, time.Since(connectStart), err)
+			}
+		},
+		TLSHandshakeStart: func() {
+			tlsStart = time.Now()
+			if t.hooks.TLSHandshakeStart != nil {
+				t.hooks.TLSHandshakeStart(req.Host)
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if t.hooks.TLSHandshakeDone != nil {
+				t.hooks.TLSHandshakeDone(req.Host, time.Since(tlsStart), state, err)
+			}
+		},
+		GotFirstResponseByte: func() {
+			if t.hooks.GotFirstResponseByte != nil {
+				t.hooks.GotFirstResponseByte(req.Host, time.Since(start))
+			}
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if t.hooks.WroteRequest != nil {
+				t.hooks.WroteRequest(req.Host, time.Since(start), info.Err)
+			}
+		},
+	}
+
+	ctx := httptrace.WithClientTrace(req.Context(), trace)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+
+	if t.hooks.Total != nil {
+		t.hooks.Total(req.Host, time.Since(start), err)
+	}
+
+	return resp, err
+}
+
+func addTrace(tr http.RoundTripper, hooks TraceHooks) http.RoundTripper {
+	return &traceRoundTripper{next: tr, hooks: hooks}
+}